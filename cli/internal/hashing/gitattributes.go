@@ -0,0 +1,172 @@
+package hashing
+
+import (
+	"bufio"
+	"strings"
+
+	"github.com/vercel/turbo/cli/internal/turbopath"
+)
+
+// Attribute names turbo understands in .gitattributes files, in addition to
+// git's own `export-ignore` (normally an export-subst/archive concern, which
+// turbo repurposes to mean "this path never affects build outputs").
+const (
+	// AttributeExportIgnore excludes a path from hashing entirely, borrowing
+	// git's own archive-export attribute.
+	AttributeExportIgnore = "export-ignore"
+	// AttributeTurboIgnore is turbo's own alias for AttributeExportIgnore,
+	// for repos that would rather not overload a git-native attribute name.
+	AttributeTurboIgnore = "turbo-ignore"
+	// AttributeTurboHash selects how a path is hashed: "skip" drops it from
+	// the result entirely, "content" always hashes the worktree content
+	// (even for a tracked, unmodified file), and "mtime" is reserved for a
+	// future fast-path that hashes file metadata instead of content.
+	AttributeTurboHash = "turbo-hash"
+)
+
+const (
+	turboHashSkip    = "skip"
+	turboHashContent = "content"
+)
+
+// gitAttributes is the combined set of .gitattributes patterns between a
+// repo root and some package directory, in git's precedence order (root
+// first, closer-to-the-file later, later entries win on conflicts).
+type gitAttributes struct {
+	// rules are stored in application order: later rules override earlier
+	// ones for the same attribute on a matching path.
+	rules []attributeRule
+}
+
+type attributeRule struct {
+	// dir is the directory (anchored to the repo root) that the
+	// .gitattributes declaring this rule lives in; pattern is matched
+	// relative to dir.
+	dir     turbopath.AnchoredUnixPath
+	pattern string
+	attrs   map[string]string
+}
+
+// readGitAttributes walks from rootPath down to packagePath (inclusive),
+// reading any .gitattributes file found at each level and combining their
+// rules in git's precedence order: root-most first, most specific last.
+func readGitAttributes(rootPath turbopath.AbsoluteSystemPath, packagePath turbopath.AnchoredSystemPath) (*gitAttributes, error) {
+	combined := &gitAttributes{}
+	pkgUnix := packagePath.ToUnixPath()
+	dirs := anchoredDirsFromRootTo(pkgUnix)
+	if pkgUnix != "" && pkgUnix != "." {
+		dirs = append(dirs, pkgUnix)
+	}
+	for _, dir := range dirs {
+		attrPath := dir.RestoreAnchor(rootPath).Join(".gitattributes")
+		content, err := attrPath.ReadFile()
+		if err != nil {
+			continue
+		}
+		rules := parseGitAttributes(dir, content)
+		combined.rules = append(combined.rules, rules...)
+	}
+	return combined, nil
+}
+
+func parseGitAttributes(dir turbopath.AnchoredUnixPath, content []byte) []attributeRule {
+	var rules []attributeRule
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		attrs := map[string]string{}
+		for _, field := range fields[1:] {
+			if strings.HasPrefix(field, "-") {
+				attrs[strings.TrimPrefix(field, "-")] = "false"
+				continue
+			}
+			if eq := strings.IndexByte(field, '='); eq != -1 {
+				attrs[field[:eq]] = field[eq+1:]
+				continue
+			}
+			attrs[field] = "true"
+		}
+		rules = append(rules, attributeRule{dir: dir, pattern: fields[0], attrs: attrs})
+	}
+	return rules
+}
+
+// get returns the combined attribute set that applies to path (anchored to
+// the same root readGitAttributes was called with), applying later,
+// more-specific rules over earlier ones.
+func (a *gitAttributes) get(path turbopath.AnchoredUnixPath) map[string]string {
+	result := map[string]string{}
+	for _, rule := range a.rules {
+		rel := strings.TrimPrefix(strings.TrimPrefix(path.ToString(), rule.dir.ToString()), "/")
+		matched, err := fsMatch(rule.pattern, rel)
+		if err != nil || !matched {
+			continue
+		}
+		for k, v := range rule.attrs {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// exportIgnored reports whether path is marked export-ignore or turbo-ignore.
+func (a *gitAttributes) exportIgnored(path turbopath.AnchoredUnixPath) bool {
+	attrs := a.get(path)
+	return attrs[AttributeExportIgnore] == "true" || attrs[AttributeTurboIgnore] == "true"
+}
+
+// hashSkipped reports whether path is marked turbo-hash=skip.
+func (a *gitAttributes) hashSkipped(path turbopath.AnchoredUnixPath) bool {
+	return a.get(path)[AttributeTurboHash] == turboHashSkip
+}
+
+// forcesContentHash reports whether path is marked turbo-hash=content, which
+// forces hashing the worktree copy even for an unmodified tracked file.
+func (a *gitAttributes) forcesContentHash(path turbopath.AnchoredUnixPath) bool {
+	return a.get(path)[AttributeTurboHash] == turboHashContent
+}
+
+// applyGitAttributes filters and rewrites hashes in place according to the
+// combined .gitattributes rules for packagePath: export-ignored and
+// skip-hashed paths are removed, and content-hashed paths are recomputed
+// directly from the worktree.
+func applyGitAttributes(rootPath turbopath.AbsoluteSystemPath, packagePath turbopath.AnchoredSystemPath, hashes map[turbopath.AnchoredUnixPath]string) error {
+	attrs, err := readGitAttributes(rootPath, packagePath)
+	if err != nil {
+		return err
+	}
+	if len(attrs.rules) == 0 {
+		return nil
+	}
+
+	algo, err := repoHashAlgo(rootPath)
+	if err != nil {
+		return err
+	}
+
+	for path := range hashes {
+		full := turbopath.AnchoredUnixPath(packagePath.ToUnixPath().ToString() + "/" + path.ToString())
+		switch {
+		case attrs.exportIgnored(full), attrs.hashSkipped(full):
+			delete(hashes, path)
+		case attrs.forcesContentHash(full):
+			content, err := path.ToSystemPath().RestoreAnchor(packagePath.RestoreAnchor(rootPath)).ReadFile()
+			if err != nil {
+				return err
+			}
+			hash, err := contentBlobHashWithAlgo(content, algo)
+			if err != nil {
+				return err
+			}
+			hashes[path] = hash
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package hashing
+
+import (
+	"testing"
+
+	"github.com/vercel/turbo/cli/internal/fs"
+	"gotest.tools/v3/assert"
+)
+
+// Test_getPackageFileHashesFromProcessingGitAttributes mirrors
+// Test_getPackageFileHashesFromProcessingGitIgnore's fixture shape: a root
+// and a package-level .gitattributes, exercising export-ignore, turbo-ignore,
+// turbo-hash=skip and turbo-hash=content.
+func Test_getPackageFileHashesFromProcessingGitAttributes(t *testing.T) {
+	repoRoot := fs.AbsoluteSystemPathFromUpstream(t.TempDir())
+	pkgDir := repoRoot.UntypedJoin("my-pkg")
+	assert.NilError(t, pkgDir.MkdirAll(0775), "CreateDir")
+
+	assert.NilError(t, repoRoot.UntypedJoin(".gitattributes").WriteFile([]byte("*.md export-ignore\n"), 0644), "WriteFile")
+	assert.NilError(t, pkgDir.UntypedJoin(".gitattributes").WriteFile([]byte(
+		"secret.txt turbo-ignore\n"+
+			"skip-me.txt turbo-hash=skip\n"+
+			"dynamic.txt turbo-hash=content\n",
+	), 0644), "WriteFile")
+
+	assert.NilError(t, pkgDir.UntypedJoin("package.json").WriteFile([]byte("{}"), 0644), "WriteFile")
+	assert.NilError(t, pkgDir.UntypedJoin("README.md").WriteFile([]byte("docs"), 0644), "WriteFile")
+	assert.NilError(t, pkgDir.UntypedJoin("secret.txt").WriteFile([]byte("shh"), 0644), "WriteFile")
+	assert.NilError(t, pkgDir.UntypedJoin("skip-me.txt").WriteFile([]byte("nope"), 0644), "WriteFile")
+	assert.NilError(t, pkgDir.UntypedJoin("dynamic.txt").WriteFile([]byte("first"), 0644), "WriteFile")
+
+	requireGitCmd(t, repoRoot, "init", ".")
+	requireGitCmd(t, repoRoot, "config", "--local", "user.name", "test")
+	requireGitCmd(t, repoRoot, "config", "--local", "user.email", "test@example.com")
+	requireGitCmd(t, repoRoot, "add", ".")
+	requireGitCmd(t, repoRoot, "commit", "-m", "foo")
+
+	// Modify dynamic.txt after committing, without staging it, so the
+	// index hash and worktree content hash diverge.
+	assert.NilError(t, pkgDir.UntypedJoin("dynamic.txt").WriteFile([]byte("second"), 0644), "WriteFile")
+
+	hashes, err := GetPackageFileHashes(repoRoot, "my-pkg", nil)
+	assert.NilError(t, err)
+
+	_, hasReadme := hashes["README.md"]
+	assert.Assert(t, !hasReadme, "expected *.md export-ignore to exclude README.md")
+	_, hasSecret := hashes["secret.txt"]
+	assert.Assert(t, !hasSecret, "expected turbo-ignore to exclude secret.txt")
+	_, hasSkipped := hashes["skip-me.txt"]
+	assert.Assert(t, !hasSkipped, "expected turbo-hash=skip to exclude skip-me.txt")
+
+	dynamicHash, ok := hashes["dynamic.txt"]
+	assert.Assert(t, ok, "expected dynamic.txt to still be hashed")
+	contentHash, err := contentBlobHash([]byte("second"))
+	assert.NilError(t, err)
+	assert.Equal(t, dynamicHash, contentHash, "expected turbo-hash=content to hash the worktree copy")
+
+	_, hasPackageJSON := hashes["package.json"]
+	assert.Assert(t, hasPackageJSON, "expected untouched files to remain")
+}
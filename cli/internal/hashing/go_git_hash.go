@@ -0,0 +1,176 @@
+package hashing
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/pkg/errors"
+
+	"github.com/vercel/turbo/cli/internal/turbopath"
+)
+
+// goGitHashFiles computes blob hashes for filesToHash without ever shelling
+// out to git. Tracked and staged files are read straight out of the index in
+// a SHA1 repo; anything else (and everything, in a SHA256 repo) falls back to
+// hashing the working tree content in-process with the same
+// `blob <len>\0<content>` framing git itself uses, via plumbing.ComputeHash
+// or contentBlobHashWithAlgo.
+//
+// go-git's plumbing.Hash is a fixed 20-byte array hard-coded to SHA1, so
+// index entries can never hold a real 32-byte SHA256 digest regardless of
+// the repo's object format; trusting entry.Hash.String() there would produce
+// a plausible-looking but wrong hash. So in a SHA256 repo every file,
+// tracked or not, is re-hashed from its working tree content instead.
+func goGitHashFiles(rootPath turbopath.AbsoluteSystemPath, filesToHash []turbopath.AnchoredSystemPath, allowMissing bool) (map[turbopath.AnchoredUnixPath]string, error) {
+	repo, err := openGoGitRepo(rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// go-git's plumbing.ComputeHash is hard-coded to SHA1. For a sha256
+	// repo, fall back to a manually computed sha256 blob hash instead.
+	algo, err := repoHashAlgo(rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := repo.Storer.Index()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read git index")
+	}
+	indexHashes := make(map[string]plumbing.Hash, len(index.Entries))
+	for _, entry := range index.Entries {
+		indexHashes[entry.Name] = entry.Hash
+	}
+
+	hashes := make(map[turbopath.AnchoredUnixPath]string, len(filesToHash))
+	for _, file := range filesToHash {
+		absPath := file.RestoreAnchor(rootPath)
+		unixPath := file.ToUnixPath()
+
+		indexHash, inIndex := indexHashes[unixPath.ToString()]
+		_, statErr := absPath.Lstat()
+		exists := statErr == nil
+
+		if inIndex && !exists {
+			// Tracked in the index but missing from the working tree.
+			if allowMissing {
+				continue
+			}
+			return nil, fmt.Errorf("%v: no such file or directory", absPath)
+		}
+
+		if inIndex && exists && algo != SHA256 {
+			hashes[unixPath] = indexHash.String()
+			continue
+		}
+
+		content, err := os.ReadFile(absPath.ToString())
+		if err != nil {
+			if allowMissing && os.IsNotExist(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "could not read %v", absPath)
+		}
+
+		if algo == SHA256 {
+			hash, err := contentBlobHashWithAlgo(content, SHA256)
+			if err != nil {
+				return nil, err
+			}
+			hashes[unixPath] = hash
+			continue
+		}
+
+		hash := plumbing.ComputeHash(plumbing.BlobObject, content)
+		hashes[unixPath] = hash.String()
+	}
+
+	return hashes, nil
+}
+
+// goGitListPackageFiles enumerates every tracked and untracked file under
+// packagePath without shelling out to `git ls-files`: tracked files come
+// straight from the index, untracked ones from a worktree walk that honors
+// .gitignore via plumbing/format/gitignore.
+func goGitListPackageFiles(rootPath turbopath.AbsoluteSystemPath, packagePath turbopath.AnchoredSystemPath) ([]turbopath.AnchoredSystemPath, error) {
+	repo, err := openGoGitRepo(rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := repo.Storer.Index()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read git index")
+	}
+
+	var files []turbopath.AnchoredSystemPath
+	for _, entry := range index.Entries {
+		anchored := turbopath.AnchoredUnixPath(entry.Name).ToSystemPath()
+		if !anchored.HasPrefix(packagePath) {
+			continue
+		}
+		// The index may still reference a file that was deleted from the
+		// working tree without being `git rm`'d; skip those.
+		if _, statErr := anchored.RestoreAnchor(rootPath).Lstat(); statErr != nil {
+			continue
+		}
+		files = append(files, anchored)
+	}
+
+	untracked, err := goGitUntrackedFiles(repo, rootPath, packagePath)
+	if err != nil {
+		return nil, err
+	}
+	return append(files, untracked...), nil
+}
+
+// openGoGitRepo opens the repository containing rootPath, searching parent
+// directories the same way `git` does when invoked from a subdirectory.
+func openGoGitRepo(rootPath turbopath.AbsoluteSystemPath) (*git.Repository, error) {
+	repo, err := git.PlainOpenWithOptions(rootPath.ToString(), &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open git repository for %v", rootPath)
+	}
+	return repo, nil
+}
+
+// goGitUntrackedFiles computes the status of the whole worktree and returns
+// the untracked paths under pkgPath that are not ignored by the repository's
+// combined .gitignore patterns (via plumbing/format/gitignore).
+func goGitUntrackedFiles(repo *git.Repository, rootPath turbopath.AbsoluteSystemPath, pkgPath turbopath.AnchoredSystemPath) ([]turbopath.AnchoredSystemPath, error) {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open worktree")
+	}
+
+	patterns, err := gitignore.ReadPatterns(worktree.Filesystem, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read .gitignore patterns")
+	}
+	matcher := gitignore.NewMatcher(patterns)
+
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not compute worktree status")
+	}
+
+	var untracked []turbopath.AnchoredSystemPath
+	for file, fileStatus := range status {
+		if fileStatus.Staging != git.Untracked && fileStatus.Worktree != git.Untracked {
+			continue
+		}
+		anchored := turbopath.AnchoredUnixPath(file).ToSystemPath()
+		if matcher.Match(strings.Split(file, "/"), false) {
+			continue
+		}
+		if anchored.HasPrefix(pkgPath) {
+			untracked = append(untracked, anchored)
+		}
+	}
+	return untracked, nil
+}
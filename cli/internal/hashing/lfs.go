@@ -0,0 +1,170 @@
+package hashing
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/vercel/turbo/cli/internal/turbopath"
+)
+
+// fsMatch reports whether rel matches the given gitattributes-style pattern.
+// This intentionally only supports the common cases (exact names, "*.ext",
+// and "dir/**"); full gitignore-style pattern semantics live with the
+// gitignore integration used for file enumeration.
+func fsMatch(pattern string, rel string) (bool, error) {
+	pattern = strings.TrimSuffix(pattern, "/")
+	if strings.HasSuffix(pattern, "/**") {
+		prefix := strings.TrimSuffix(pattern, "/**")
+		return rel == prefix || strings.HasPrefix(rel, prefix+"/"), nil
+	}
+	if !strings.Contains(pattern, "/") {
+		return filepath.Match(pattern, filepath.Base(rel))
+	}
+	return filepath.Match(pattern, rel)
+}
+
+// lfsPointerSignature is the first line of every Git LFS pointer file, per
+// https://github.com/git-lfs/git-lfs/blob/main/docs/spec.md.
+const lfsPointerSignature = "version https://git-lfs.github.com/spec/v1"
+
+// lfsPointer is the subset of a parsed LFS pointer file that we need to
+// derive a content-accurate hash.
+type lfsPointer struct {
+	oid  string
+	size int64
+}
+
+// parseLFSPointer returns the parsed pointer if content looks like a Git LFS
+// pointer file, and ok=false otherwise. Content that starts with the pointer
+// signature is scanned line by line, to its end, for the oid and size keys.
+func parseLFSPointer(content []byte) (pointer lfsPointer, ok bool) {
+	if !bytes.HasPrefix(content, []byte(lfsPointerSignature)) {
+		return lfsPointer{}, false
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	var oid string
+	var size int64
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "oid sha256:"):
+			oid = strings.TrimPrefix(line, "oid sha256:")
+		case strings.HasPrefix(line, "size "):
+			if _, err := fmt.Sscanf(line, "size %d", &size); err != nil {
+				return lfsPointer{}, false
+			}
+		}
+	}
+
+	if oid == "" || size == 0 {
+		return lfsPointer{}, false
+	}
+	return lfsPointer{oid: oid, size: size}, true
+}
+
+// hash returns the composite hash turbo uses in place of the pointer blob's
+// own SHA-1, so that the cache key changes whenever the LFS-tracked content
+// changes rather than whenever the pointer file does.
+func (p lfsPointer) hash() string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(fmt.Sprintf("lfs:sha256:%s:%d", p.oid, p.size))))
+}
+
+// HashStrategy controls how a single file's content is turned into a hash
+// when LFS resolution is enabled.
+type HashStrategy string
+
+const (
+	// Pointer hashes the pointer blob itself, i.e. turbo's historical
+	// behavior. The cache key will not change when the LFS-tracked content
+	// changes, only when the pointer file does.
+	Pointer HashStrategy = "pointer"
+	// OID derives the hash from the pointer's declared oid+size, so the
+	// cache key tracks the real content even when it hasn't been pulled
+	// into the local LFS object store.
+	OID HashStrategy = "oid"
+	// ContentIfPresent hashes the materialized file in the worktree when it
+	// is not a pointer (the common case for non-LFS files), and falls back
+	// to OID behavior for files that are still pointers.
+	ContentIfPresent HashStrategy = "content-if-present"
+)
+
+// resolveLFSHash inspects content for an LFS pointer and, depending on
+// strategy, returns a replacement hash to use instead of the blob's own
+// SHA-1. ok is false when no substitution should happen and the caller
+// should keep using the blob hash it already computed.
+func resolveLFSHash(content []byte, strategy HashStrategy) (hash string, ok bool) {
+	pointer, isPointer := parseLFSPointer(content)
+	switch strategy {
+	case Pointer, "":
+		return "", false
+	case OID, ContentIfPresent:
+		if !isPointer {
+			return "", false
+		}
+		return pointer.hash(), true
+	default:
+		return "", false
+	}
+}
+
+// isLFSDeclaredPath reports whether path matches a `filter=lfs` pattern in
+// any .gitattributes file between rootPath and the file's directory. This
+// gates resolveLFSHashesInPlace, so that a file is only ever reinterpreted as
+// an LFS pointer when .gitattributes actually declares it LFS-tracked. This
+// is a minimal reader that only looks for the `filter=lfs` attribute; see
+// gitattributes.go for the general-purpose reader used for export-ignore and
+// turbo-hash.
+func isLFSDeclaredPath(rootPath turbopath.AbsoluteSystemPath, path turbopath.AnchoredUnixPath) bool {
+	for _, dir := range anchoredDirsFromRootTo(path) {
+		attrPath := dir.RestoreAnchor(rootPath).Join(".gitattributes")
+		content, err := attrPath.ReadFile()
+		if err != nil {
+			continue
+		}
+		if gitattributesDeclaresFilterLFS(content, path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// anchoredDirsFromRootTo returns every directory from the repo root down to
+// (and including) path's own directory, in that order, mirroring the
+// precedence git uses when combining .gitattributes files.
+func anchoredDirsFromRootTo(path turbopath.AnchoredUnixPath) []turbopath.AnchoredUnixPath {
+	segments := strings.Split(path.ToString(), "/")
+	dirs := []turbopath.AnchoredUnixPath{""}
+	for i := 1; i < len(segments); i++ {
+		dirs = append(dirs, turbopath.AnchoredUnixPath(strings.Join(segments[:i], "/")))
+	}
+	return dirs
+}
+
+// gitattributesDeclaresFilterLFS checks whether any line in a .gitattributes
+// file living in dir matches path with `filter=lfs`.
+func gitattributesDeclaresFilterLFS(content []byte, path turbopath.AnchoredUnixPath, dir turbopath.AnchoredUnixPath) bool {
+	rel := strings.TrimPrefix(strings.TrimPrefix(path.ToString(), dir.ToString()), "/")
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		pattern, attrs := fields[0], fields[1:]
+		matched, err := fsMatch(pattern, rel)
+		if err != nil || !matched {
+			continue
+		}
+		for _, attr := range attrs {
+			if attr == "filter=lfs" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package hashing
+
+import (
+	"testing"
+
+	"github.com/vercel/turbo/cli/internal/fs"
+	"gotest.tools/v3/assert"
+)
+
+func TestParseLFSPointer(t *testing.T) {
+	pointerContents := "version https://git-lfs.github.com/spec/v1\n" +
+		"oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\n" +
+		"size 12345\n"
+
+	pointer, ok := parseLFSPointer([]byte(pointerContents))
+	assert.Assert(t, ok, "expected pointer contents to parse")
+	assert.Equal(t, pointer.oid, "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393")
+	assert.Equal(t, pointer.size, int64(12345))
+
+	_, ok = parseLFSPointer([]byte("just some regular file contents"))
+	assert.Assert(t, !ok, "expected non-pointer contents to fail parsing")
+}
+
+func TestResolveLFSHash(t *testing.T) {
+	pointerContents := []byte("version https://git-lfs.github.com/spec/v1\n" +
+		"oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\n" +
+		"size 12345\n")
+	regularContents := []byte("just some regular file contents")
+
+	_, ok := resolveLFSHash(pointerContents, Pointer)
+	assert.Assert(t, !ok, "Pointer strategy never substitutes")
+
+	oidHash, ok := resolveLFSHash(pointerContents, OID)
+	assert.Assert(t, ok)
+
+	contentIfPresentHash, ok := resolveLFSHash(pointerContents, ContentIfPresent)
+	assert.Assert(t, ok)
+	assert.Equal(t, oidHash, contentIfPresentHash)
+
+	_, ok = resolveLFSHash(regularContents, OID)
+	assert.Assert(t, !ok, "non-pointer content should not be substituted under OID")
+
+	_, ok = resolveLFSHash(regularContents, ContentIfPresent)
+	assert.Assert(t, !ok, "non-pointer content should not be substituted under ContentIfPresent")
+}
+
+// TestGetPackageFileHashesWithOptionsResolvesLFS covers a mixed repo: one
+// regular file and one LFS pointer file, both tracked, where ResolveLFS
+// should only change the hash of the pointer file.
+func TestGetPackageFileHashesWithOptionsResolvesLFS(t *testing.T) {
+	repoRoot := fs.AbsoluteSystemPathFromUpstream(t.TempDir())
+	pkgDir := repoRoot.UntypedJoin("my-pkg")
+	assert.NilError(t, pkgDir.MkdirAll(0775), "CreateDir")
+
+	assert.NilError(t, pkgDir.UntypedJoin("package.json").WriteFile([]byte("{}"), 0644), "WriteFile")
+	assert.NilError(t, pkgDir.UntypedJoin("regular-file").WriteFile([]byte("just some regular file contents"), 0644), "WriteFile")
+
+	pointerContents := "version https://git-lfs.github.com/spec/v1\n" +
+		"oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\n" +
+		"size 12345\n"
+	assert.NilError(t, pkgDir.UntypedJoin("dataset.bin").WriteFile([]byte(pointerContents), 0644), "WriteFile")
+	assert.NilError(t, pkgDir.UntypedJoin(".gitattributes").WriteFile([]byte("dataset.bin filter=lfs diff=lfs merge=lfs -text\n"), 0644), "WriteFile")
+
+	requireGitCmd(t, repoRoot, "init", ".")
+	requireGitCmd(t, repoRoot, "config", "--local", "user.name", "test")
+	requireGitCmd(t, repoRoot, "config", "--local", "user.email", "test@example.com")
+	requireGitCmd(t, repoRoot, "add", ".")
+	requireGitCmd(t, repoRoot, "commit", "-m", "foo")
+
+	withoutLFS, err := GetPackageFileHashesWithOptions(repoRoot, "my-pkg", nil, nil)
+	assert.NilError(t, err)
+
+	withLFS, err := GetPackageFileHashesWithOptions(repoRoot, "my-pkg", nil, &PackageDepsOptions{ResolveLFS: true})
+	assert.NilError(t, err)
+
+	assert.Equal(t, withoutLFS["regular-file"], withLFS["regular-file"])
+	assert.Assert(t, withoutLFS["dataset.bin"] != withLFS["dataset.bin"],
+		"ResolveLFS should change the hash of a pointer file")
+	assert.Assert(t, isLFSDeclaredPath(repoRoot, "my-pkg/dataset.bin"), "expected .gitattributes to declare dataset.bin as filter=lfs")
+}
+
+// TestGetPackageFileHashesWithOptionsIgnoresUndeclaredPointerLookingFiles
+// covers a file whose content happens to start with the LFS pointer
+// signature but whose path is never declared filter=lfs in .gitattributes:
+// ResolveLFS must leave its hash alone rather than treating it as LFS-tracked
+// on content alone.
+func TestGetPackageFileHashesWithOptionsIgnoresUndeclaredPointerLookingFiles(t *testing.T) {
+	repoRoot := fs.AbsoluteSystemPathFromUpstream(t.TempDir())
+	pkgDir := repoRoot.UntypedJoin("my-pkg")
+	assert.NilError(t, pkgDir.MkdirAll(0775), "CreateDir")
+
+	assert.NilError(t, pkgDir.UntypedJoin("package.json").WriteFile([]byte("{}"), 0644), "WriteFile")
+
+	pointerContents := "version https://git-lfs.github.com/spec/v1\n" +
+		"oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\n" +
+		"size 12345\n"
+	assert.NilError(t, pkgDir.UntypedJoin("not-actually-lfs.bin").WriteFile([]byte(pointerContents), 0644), "WriteFile")
+
+	requireGitCmd(t, repoRoot, "init", ".")
+	requireGitCmd(t, repoRoot, "config", "--local", "user.name", "test")
+	requireGitCmd(t, repoRoot, "config", "--local", "user.email", "test@example.com")
+	requireGitCmd(t, repoRoot, "add", ".")
+	requireGitCmd(t, repoRoot, "commit", "-m", "foo")
+
+	withoutLFS, err := GetPackageFileHashesWithOptions(repoRoot, "my-pkg", nil, nil)
+	assert.NilError(t, err)
+
+	withLFS, err := GetPackageFileHashesWithOptions(repoRoot, "my-pkg", nil, &PackageDepsOptions{ResolveLFS: true})
+	assert.NilError(t, err)
+
+	assert.Equal(t, withoutLFS["not-actually-lfs.bin"], withLFS["not-actually-lfs.bin"],
+		"ResolveLFS should not touch a pointer-looking file whose path isn't declared filter=lfs")
+}
@@ -0,0 +1,157 @@
+package hashing
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/vercel/turbo/cli/internal/turbopath"
+)
+
+// HashAlgo identifies the object hashing algorithm a repository was
+// initialized with. Git defaults to SHA1; `git init --object-format=sha256`
+// (git 2.42+) produces a SHA256 repo, whose blob hashes are a different
+// length and can't be compared against a SHA1 repo's.
+type HashAlgo string
+
+const (
+	// SHA1 is git's historical, and still default, object format.
+	SHA1 HashAlgo = "sha1"
+	// SHA256 is the newer object format available behind
+	// `extensions.objectFormat = sha256`.
+	SHA256 HashAlgo = "sha256"
+)
+
+// PackageFileHashResult is the richer return type that exposes which hash
+// algorithm produced a set of hashes, for callers who need to avoid mixing
+// cache keys from repos using different object formats.
+type PackageFileHashResult struct {
+	Hashes map[turbopath.AnchoredUnixPath]string
+	Algo   HashAlgo
+}
+
+// repoHashAlgo detects the object format a repository uses by reading
+// `extensions.objectFormat` directly out of its `.git/config`, rather than
+// shelling out to `git rev-parse --show-object-format`. That keeps it usable
+// from the GoGit backend, whose whole point is to avoid forking a git
+// subprocess per call.
+func repoHashAlgo(rootPath turbopath.AbsoluteSystemPath) (HashAlgo, error) {
+	gitDir, err := resolveGitDir(rootPath)
+	if err != nil {
+		return "", err
+	}
+	content, err := gitDir.UntypedJoin("config").ReadFile()
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read git config for %v", rootPath)
+	}
+	return parseObjectFormat(content), nil
+}
+
+// resolveGitDir locates the `.git` directory for the repository containing
+// rootPath, searching parent directories the same way `git` itself does, and
+// following the `gitdir: ...` pointer file git leaves behind for worktrees
+// and submodules in place of a real `.git` directory.
+func resolveGitDir(rootPath turbopath.AbsoluteSystemPath) (turbopath.AbsoluteSystemPath, error) {
+	dir := rootPath
+	for {
+		candidate := dir.UntypedJoin(".git")
+		info, err := candidate.Lstat()
+		if err == nil {
+			if info.IsDir() {
+				return candidate, nil
+			}
+			return resolveGitDirFile(candidate)
+		}
+		parent := turbopath.AbsoluteSystemPath(filepath.Dir(dir.ToString()))
+		if parent == dir {
+			return "", fmt.Errorf("could not find a .git directory above %v", rootPath)
+		}
+		dir = parent
+	}
+}
+
+// resolveGitDirFile reads a `gitdir: <path>` pointer file (used in place of a
+// real .git directory for worktrees and submodules) and resolves it to the
+// actual git directory.
+func resolveGitDirFile(candidate turbopath.AbsoluteSystemPath) (turbopath.AbsoluteSystemPath, error) {
+	content, err := candidate.ReadFile()
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read %v", candidate)
+	}
+	const prefix = "gitdir: "
+	line := strings.TrimSpace(string(content))
+	if !strings.HasPrefix(line, prefix) {
+		return "", fmt.Errorf("unrecognized .git file at %v", candidate)
+	}
+	target := strings.TrimPrefix(line, prefix)
+	if filepath.IsAbs(target) {
+		return turbopath.AbsoluteSystemPath(filepath.Clean(target)), nil
+	}
+	return turbopath.AbsoluteSystemPath(filepath.Clean(filepath.Join(filepath.Dir(candidate.ToString()), target))), nil
+}
+
+// parseObjectFormat reads the `objectFormat` key out of a git config's
+// `[extensions]` section. Absent the key entirely (the overwhelming majority
+// of repos, pre-git-2.42), the object format is SHA1.
+func parseObjectFormat(content []byte) HashAlgo {
+	inExtensions := false
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			inExtensions = strings.EqualFold(strings.Trim(line, "[]"), "extensions")
+			continue
+		}
+		if !inExtensions {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if strings.EqualFold(strings.TrimSpace(key), "objectformat") && strings.EqualFold(strings.TrimSpace(value), "sha256") {
+			return SHA256
+		}
+	}
+	return SHA1
+}
+
+// contentBlobHashWithAlgo is contentBlobHash generalized to the repo's
+// object format: sha1("blob <len>\0<content>") or the sha256 equivalent.
+func contentBlobHashWithAlgo(content []byte, algo HashAlgo) (string, error) {
+	if algo != SHA256 {
+		return contentBlobHash(content)
+	}
+	header := fmt.Sprintf("blob %d\x00", len(content))
+	h := sha256.New()
+	if _, err := h.Write([]byte(header)); err != nil {
+		return "", err
+	}
+	if _, err := h.Write(content); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// GetPackageFileHashesDetailed is GetPackageFileHashesWithOptions, but also
+// reports which HashAlgo the repository uses, so callers can keep cache keys
+// from different object formats from colliding.
+func GetPackageFileHashesDetailed(rootPath turbopath.AbsoluteSystemPath, packagePath turbopath.AnchoredSystemPath, inputPatterns []string, opts *PackageDepsOptions) (*PackageFileHashResult, error) {
+	algo, err := repoHashAlgo(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	hashes, err := GetPackageFileHashesWithOptions(rootPath, packagePath, inputPatterns, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &PackageFileHashResult{Hashes: hashes, Algo: algo}, nil
+}
@@ -0,0 +1,116 @@
+package hashing
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os/exec"
+	"testing"
+
+	"github.com/vercel/turbo/cli/internal/fs"
+	"github.com/vercel/turbo/cli/internal/turbopath"
+	"gotest.tools/v3/assert"
+)
+
+// supportsSha256Repos reports whether the installed git can create
+// --object-format=sha256 repositories (git 2.42+).
+func supportsSha256Repos(t *testing.T) bool {
+	t.Helper()
+	dir := t.TempDir()
+	cmd := exec.Command("git", "init", "--object-format=sha256", ".")
+	cmd.Dir = dir
+	return cmd.Run() == nil
+}
+
+func TestRepoHashAlgoSha256(t *testing.T) {
+	if !supportsSha256Repos(t) {
+		t.Skip("installed git does not support --object-format=sha256")
+	}
+
+	repoRoot := fs.AbsoluteSystemPathFromUpstream(t.TempDir())
+	requireGitCmd(t, repoRoot, "init", "--object-format=sha256", ".")
+	requireGitCmd(t, repoRoot, "config", "--local", "user.name", "test")
+	requireGitCmd(t, repoRoot, "config", "--local", "user.email", "test@example.com")
+
+	algo, err := repoHashAlgo(repoRoot)
+	assert.NilError(t, err)
+	assert.Equal(t, algo, SHA256)
+}
+
+func TestRepoHashAlgoSha1Default(t *testing.T) {
+	repoRoot := fs.AbsoluteSystemPathFromUpstream(t.TempDir())
+	requireGitCmd(t, repoRoot, "init", ".")
+
+	algo, err := repoHashAlgo(repoRoot)
+	assert.NilError(t, err)
+	assert.Equal(t, algo, SHA1)
+}
+
+// TestGetPackageDepsSha256 mirrors TestGetPackageDeps's base case against a
+// sha256 repo, verifying the emitted hashes are 64-char hex rather than the
+// usual 40-char sha1.
+func TestGetPackageDepsSha256(t *testing.T) {
+	if !supportsSha256Repos(t) {
+		t.Skip("installed git does not support --object-format=sha256")
+	}
+
+	repoRoot := fs.AbsoluteSystemPathFromUpstream(t.TempDir())
+	pkgDir := repoRoot.UntypedJoin("my-pkg")
+	assert.NilError(t, pkgDir.MkdirAll(0775), "CreateDir")
+	assert.NilError(t, pkgDir.UntypedJoin("package.json").WriteFile([]byte("{}"), 0644), "WriteFile")
+	assert.NilError(t, pkgDir.UntypedJoin("committed-file").WriteFile([]byte("committed bytes"), 0644), "WriteFile")
+
+	requireGitCmd(t, repoRoot, "init", "--object-format=sha256", ".")
+	requireGitCmd(t, repoRoot, "config", "--local", "user.name", "test")
+	requireGitCmd(t, repoRoot, "config", "--local", "user.email", "test@example.com")
+	requireGitCmd(t, repoRoot, "add", ".")
+	requireGitCmd(t, repoRoot, "commit", "-m", "foo")
+
+	result, err := GetPackageFileHashesDetailed(repoRoot, "my-pkg", nil, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, result.Algo, SHA256)
+	for path, hash := range result.Hashes {
+		assert.Equal(t, len(hash), 64, "expected a 64-char sha256 hex digest for %v, got %v", path, hash)
+	}
+}
+
+// TestGoGitHashFilesSha256 exercises goGitHashFiles's manual content-hashing
+// branch for both a tracked and an untracked file in a sha256 repo. Neither
+// can be trusted from go-git's index: index entries decode into
+// plumbing.Hash, a fixed 20-byte array hard-coded to SHA1, so even a
+// committed file has to be re-hashed from its working tree content via
+// contentBlobHashWithAlgo. The Git-exec-backed TestGetPackageDepsSha256 above
+// never reaches this branch, since `git hash-object` does the sha256 work
+// natively there.
+func TestGoGitHashFilesSha256(t *testing.T) {
+	if !supportsSha256Repos(t) {
+		t.Skip("installed git does not support --object-format=sha256")
+	}
+
+	SetHashBackend(GoGit)
+	defer SetHashBackend(Git)
+
+	repoRoot := fs.AbsoluteSystemPathFromUpstream(t.TempDir())
+	requireGitCmd(t, repoRoot, "init", "--object-format=sha256", ".")
+	requireGitCmd(t, repoRoot, "config", "--local", "user.name", "test")
+	requireGitCmd(t, repoRoot, "config", "--local", "user.email", "test@example.com")
+
+	trackedContent := []byte("tracked bytes")
+	assert.NilError(t, repoRoot.UntypedJoin("tracked-file").WriteFile(trackedContent, 0644), "WriteFile")
+	requireGitCmd(t, repoRoot, "add", ".")
+	requireGitCmd(t, repoRoot, "commit", "-m", "foo")
+
+	untrackedContent := []byte("untracked bytes")
+	assert.NilError(t, repoRoot.UntypedJoin("untracked-file").WriteFile(untrackedContent, 0644), "WriteFile")
+
+	hashes, err := GetHashesForFiles(repoRoot, []turbopath.AnchoredSystemPath{"tracked-file", "untracked-file"})
+	assert.NilError(t, err)
+
+	blobHash := func(content []byte) string {
+		header := fmt.Sprintf("blob %d\x00", len(content))
+		return fmt.Sprintf("%x", sha256.Sum256(append([]byte(header), content...)))
+	}
+
+	assert.Equal(t, hashes["tracked-file"], blobHash(trackedContent))
+	assert.Equal(t, len(hashes["tracked-file"]), 64)
+	assert.Equal(t, hashes["untracked-file"], blobHash(untrackedContent))
+}
@@ -0,0 +1,41 @@
+package hashing
+
+// PackageDepsOptions holds the extra, opt-in behaviors that
+// GetPackageFileHashesWithOptions supports on top of the defaults used by
+// GetPackageFileHashes. The zero value matches GetPackageFileHashes exactly.
+type PackageDepsOptions struct {
+	// ResolveLFS, when true, detects Git LFS pointer files among the hashed
+	// paths and substitutes a hash derived from the pointer's declared
+	// content (oid+size) instead of the pointer blob's own SHA-1, per
+	// HashStrategy.
+	ResolveLFS bool
+
+	// HashStrategy controls how LFS-tracked files are hashed when
+	// ResolveLFS is set. Defaults to ContentIfPresent.
+	HashStrategy HashStrategy
+
+	// SubmoduleMode controls how package directories that overlap a git
+	// submodule are hashed. Defaults to Ignore, turbo's historical
+	// behavior.
+	SubmoduleMode SubmoduleMode
+}
+
+// defaultedHashStrategy returns the strategy to use, applying the default
+// when the caller left it unset.
+func (o *PackageDepsOptions) defaultedHashStrategy() HashStrategy {
+	if o == nil || o.HashStrategy == "" {
+		return ContentIfPresent
+	}
+	return o.HashStrategy
+}
+
+func (o *PackageDepsOptions) resolveLFS() bool {
+	return o != nil && o.ResolveLFS
+}
+
+func (o *PackageDepsOptions) defaultedSubmoduleMode() SubmoduleMode {
+	if o == nil || o.SubmoduleMode == "" {
+		return Ignore
+	}
+	return o.SubmoduleMode
+}
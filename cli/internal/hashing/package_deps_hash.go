@@ -0,0 +1,367 @@
+// Package hashing deals with hashing filesystem state, either to support
+// caching for tasks, or for other purposes.
+package hashing
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/vercel/turbo/cli/internal/fs"
+	"github.com/vercel/turbo/cli/internal/turbopath"
+)
+
+// HashBackend identifies which implementation GetPackageFileHashes and
+// GetHashesForFiles use to produce git-compatible blob hashes.
+type HashBackend string
+
+const (
+	// Git shells out to the system git binary for every hash. This is the
+	// default: it matches git's own hashing exactly and needs no extra
+	// dependencies, at the cost of a fork+exec per batch of files.
+	Git HashBackend = "git"
+	// GoGit reads the repository directly via go-git and computes blob
+	// hashes in-process, without ever invoking the git binary. Useful in
+	// environments where git isn't on PATH (minimal CI images, FaaS) and
+	// faster on large monorepos, since it avoids repeated fork+exec.
+	GoGit HashBackend = "go-git"
+)
+
+// hashBackendEnvVar lets operators opt into the go-git backend without
+// touching call sites.
+const hashBackendEnvVar = "TURBO_HASH_BACKEND"
+
+// activeHashBackend is the backend used by GetPackageFileHashes and
+// GetHashesForFiles. It defaults to Git for parity with existing behavior.
+var activeHashBackend = hashBackendFromEnv()
+
+func hashBackendFromEnv() HashBackend {
+	if HashBackend(os.Getenv(hashBackendEnvVar)) == GoGit {
+		return GoGit
+	}
+	return Git
+}
+
+// SetHashBackend overrides which backend subsequent hashing calls use. It is
+// exported primarily so tests (and advanced callers) can exercise both
+// backends against the same inputs.
+func SetHashBackend(backend HashBackend) {
+	activeHashBackend = backend
+}
+
+// GetPackageFileHashes is a function that calculates the hashes of all the
+// files in a package, with some exceptions (.gitignore, node_modules, etc).
+// If inputs are provided, the hashes for only the files matching those
+// inputs are computed instead.
+func GetPackageFileHashes(rootPath turbopath.AbsoluteSystemPath, packagePath turbopath.AnchoredSystemPath, inputPatterns []string) (map[turbopath.AnchoredUnixPath]string, error) {
+	return GetPackageFileHashesWithOptions(rootPath, packagePath, inputPatterns, nil)
+}
+
+// GetPackageFileHashesWithOptions is GetPackageFileHashes with access to the
+// opt-in behaviors in PackageDepsOptions (LFS resolution, submodule
+// handling, etc).
+func GetPackageFileHashesWithOptions(rootPath turbopath.AbsoluteSystemPath, packagePath turbopath.AnchoredSystemPath, inputPatterns []string, opts *PackageDepsOptions) (map[turbopath.AnchoredUnixPath]string, error) {
+	if len(inputPatterns) == 0 {
+		return getPackageFileHashesFromGitIndex(rootPath, packagePath, opts)
+	}
+	return getPackageFileHashesFromInputs(rootPath, packagePath, inputPatterns, opts)
+}
+
+// getPackageFileHashesFromGitIndex hashes every tracked and untracked file
+// that `git` considers part of the package, honoring .gitignore along the
+// way, then maps the resulting hashes back to package-relative paths.
+func getPackageFileHashesFromGitIndex(rootPath turbopath.AbsoluteSystemPath, packagePath turbopath.AnchoredSystemPath, opts *PackageDepsOptions) (map[turbopath.AnchoredUnixPath]string, error) {
+	toHash, err := listPackageFiles(rootPath, packagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes, err := manuallyHashFiles(rootPath, toHash, false, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode := opts.defaultedSubmoduleMode(); mode != Ignore {
+		submodules, err := submodulesOverlapping(rootPath, packagePath)
+		if err != nil {
+			return nil, err
+		}
+		for _, sub := range submodules {
+			subHashes, err := hashSubmodule(rootPath, sub, packagePath, opts)
+			if err != nil {
+				return nil, err
+			}
+			for path, hash := range subHashes {
+				hashes[path] = hash
+			}
+		}
+	}
+
+	relHashes := stripPackageAnchor(packagePath, hashes)
+	if err := applyGitAttributes(rootPath, packagePath, relHashes); err != nil {
+		return nil, err
+	}
+	return relHashes, nil
+}
+
+// getPackageFileHashesFromInputs expands the given glob patterns (relative
+// to packagePath, allowing traversal outside of it via "../") and hashes the
+// matching files, always including the package's package.json.
+func getPackageFileHashesFromInputs(rootPath turbopath.AbsoluteSystemPath, packagePath turbopath.AnchoredSystemPath, inputPatterns []string, opts *PackageDepsOptions) (map[turbopath.AnchoredUnixPath]string, error) {
+	pkgPath := packagePath.RestoreAnchor(rootPath)
+
+	patterns := make([]string, 0, len(inputPatterns)+1)
+	patterns = append(patterns, inputPatterns...)
+	patterns = append(patterns, "package.json")
+
+	matches, err := globby(pkgPath, patterns)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not expand input patterns for %v", pkgPath)
+	}
+
+	toHash := make([]turbopath.AnchoredSystemPath, 0, len(matches))
+	for _, match := range matches {
+		rel, err := filepath.Rel(pkgPath.ToString(), match)
+		if err != nil {
+			return nil, err
+		}
+		toHash = append(toHash, turbopath.AnchoredSystemPath(rel))
+	}
+
+	hashes, err := manuallyHashFiles(pkgPath, toHash, true, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyGitAttributes(rootPath, packagePath, hashes); err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// listPackageFiles enumerates every tracked and untracked file (honoring
+// .gitignore) that lives under packagePath, using whichever HashBackend is
+// active.
+func listPackageFiles(rootPath turbopath.AbsoluteSystemPath, packagePath turbopath.AnchoredSystemPath) ([]turbopath.AnchoredSystemPath, error) {
+	if activeHashBackend == GoGit {
+		return goGitListPackageFiles(rootPath, packagePath)
+	}
+	return gitLsFilesPackageFiles(rootPath, packagePath)
+}
+
+// gitLsFilesPackageFiles is the Git backend's file enumeration: it shells
+// out to `git ls-files`, which already honors .gitignore and combines
+// tracked + untracked files for us.
+func gitLsFilesPackageFiles(rootPath turbopath.AbsoluteSystemPath, packagePath turbopath.AnchoredSystemPath) ([]turbopath.AnchoredSystemPath, error) {
+	pkgPath := packagePath.RestoreAnchor(rootPath)
+
+	cmd := exec.Command("git", "ls-files", "--cached", "--others", "--exclude-standard", "-z", "--", ".")
+	cmd.Dir = pkgPath.ToString()
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not list files for %v", pkgPath)
+	}
+
+	var toHash []turbopath.AnchoredSystemPath
+	for _, line := range strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00") {
+		if line == "" {
+			continue
+		}
+		relPath := turbopath.AnchoredUnixPathFromUpstream(line).ToSystemPath()
+		anchoredPath := packagePath.Join(relPath)
+		// `git ls-files --cached` still reports files that were deleted from
+		// the working tree but never removed from the index. Skip them, and
+		// skip directories (submodule gitlinks), which `git hash-object`
+		// cannot hash.
+		if info, statErr := anchoredPath.RestoreAnchor(rootPath).Lstat(); statErr != nil || info.IsDir() {
+			continue
+		}
+		toHash = append(toHash, anchoredPath)
+	}
+	return toHash, nil
+}
+
+// stripPackageAnchor rewrites hashes, which are anchored to the repo root,
+// so that they are anchored to packagePath instead.
+func stripPackageAnchor(packagePath turbopath.AnchoredSystemPath, hashes map[turbopath.AnchoredUnixPath]string) map[turbopath.AnchoredUnixPath]string {
+	prefix := packagePath.ToUnixPath().ToString()
+	out := make(map[turbopath.AnchoredUnixPath]string, len(hashes))
+	for path, hash := range hashes {
+		rel := strings.TrimPrefix(path.ToString(), prefix+"/")
+		out[turbopath.AnchoredUnixPath(rel)] = hash
+	}
+	return out
+}
+
+// GetHashesForFiles hashes every file passed in, failing if any of them are
+// missing. Paths are resolved relative to rootPath, and the returned map
+// keys are unix-style paths relative to rootPath.
+func GetHashesForFiles(rootPath turbopath.AbsoluteSystemPath, filesToHash []turbopath.AnchoredSystemPath) (map[turbopath.AnchoredUnixPath]string, error) {
+	return manuallyHashFiles(rootPath, filesToHash, false, nil)
+}
+
+// GetHashesForExistingFiles hashes every file passed in that still exists on
+// disk, silently skipping any that do not.
+func GetHashesForExistingFiles(rootPath turbopath.AbsoluteSystemPath, filesToHash []turbopath.AnchoredSystemPath) (map[turbopath.AnchoredUnixPath]string, error) {
+	return manuallyHashFiles(rootPath, filesToHash, true, nil)
+}
+
+// manuallyHashFiles hashes the given files using the active HashBackend, then
+// applies any opt-in post-processing (currently just LFS resolution)
+// described by opts.
+func manuallyHashFiles(rootPath turbopath.AbsoluteSystemPath, files []turbopath.AnchoredSystemPath, allowMissing bool, opts *PackageDepsOptions) (map[turbopath.AnchoredUnixPath]string, error) {
+	if len(files) == 0 {
+		return map[turbopath.AnchoredUnixPath]string{}, nil
+	}
+
+	var hashes map[turbopath.AnchoredUnixPath]string
+	var err error
+	switch activeHashBackend {
+	case GoGit:
+		hashes, err = goGitHashFiles(rootPath, files, allowMissing)
+	default:
+		hashes, err = gitHashObject(rootPath, files, allowMissing)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.resolveLFS() {
+		if err := resolveLFSHashesInPlace(rootPath, hashes, opts.defaultedHashStrategy()); err != nil {
+			return nil, err
+		}
+	}
+	return hashes, nil
+}
+
+// resolveLFSHashesInPlace rewrites any LFS pointer hashes in hashes to the
+// composite oid+size hash dictated by strategy. Only paths a .gitattributes
+// declares `filter=lfs` are considered candidates, so a file that merely
+// starts with the pointer signature by coincidence is never reinterpreted as
+// LFS-tracked.
+func resolveLFSHashesInPlace(rootPath turbopath.AbsoluteSystemPath, hashes map[turbopath.AnchoredUnixPath]string, strategy HashStrategy) error {
+	for path := range hashes {
+		if !isLFSDeclaredPath(rootPath, path) {
+			continue
+		}
+		absPath := path.ToSystemPath().RestoreAnchor(rootPath)
+		content, err := absPath.ReadFile()
+		if err != nil {
+			// The file may no longer exist (e.g. it was hashed from the git
+			// index); nothing to resolve in that case.
+			continue
+		}
+		if resolved, ok := resolveLFSHash(content, strategy); ok {
+			hashes[path] = resolved
+		}
+	}
+	return nil
+}
+
+// gitHashObject hashes the given files by shelling out to
+// `git hash-object --stdin-paths`, which is what git itself uses to compute
+// blob hashes. Each path is fed to stdin relative to rootPath so that the
+// returned map is keyed consistently regardless of the caller's cwd.
+func gitHashObject(rootPath turbopath.AbsoluteSystemPath, filesToHash []turbopath.AnchoredSystemPath, allowMissing bool) (map[turbopath.AnchoredUnixPath]string, error) {
+	unixPaths := make([]turbopath.AnchoredUnixPath, len(filesToHash))
+	for i, file := range filesToHash {
+		unixPaths[i] = file.ToUnixPath()
+	}
+
+	if allowMissing {
+		filtered := make([]turbopath.AnchoredUnixPath, 0, len(unixPaths))
+		for i, file := range filesToHash {
+			if _, err := file.RestoreAnchor(rootPath).Lstat(); err == nil {
+				filtered = append(filtered, unixPaths[i])
+			}
+		}
+		unixPaths = filtered
+	}
+
+	if len(unixPaths) == 0 {
+		return map[turbopath.AnchoredUnixPath]string{}, nil
+	}
+
+	cmd := exec.Command("git", "hash-object", "--stdin-paths")
+	cmd.Dir = rootPath.ToString()
+
+	var stdin bytes.Buffer
+	for _, path := range unixPaths {
+		// hash-object unquotes C-style quoted lines, so quote any path that
+		// a plain newline-delimited line would split or misread.
+		line := path.ToString()
+		if strings.ContainsAny(line, "\n\"\\") {
+			line = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(line) + `"`
+		}
+		stdin.WriteString(line)
+		stdin.WriteString("\n")
+	}
+	cmd.Stdin = &stdin
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to hash files in %v", rootPath)
+	}
+
+	lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	if len(lines) != len(unixPaths) {
+		return nil, fmt.Errorf("expected %v hashes, received %v", len(unixPaths), len(lines))
+	}
+
+	hashes := make(map[turbopath.AnchoredUnixPath]string, len(unixPaths))
+	for i, path := range unixPaths {
+		hashes[path] = lines[i]
+	}
+	return hashes, nil
+}
+
+// contentBlobHash computes the same hash git itself would for content as a
+// standalone blob, i.e. sha1("blob <len>\0<content>").
+func contentBlobHash(content []byte) (string, error) {
+	header := fmt.Sprintf("blob %d\x00", len(content))
+	h := sha1.New()
+	if _, err := h.Write([]byte(header)); err != nil {
+		return "", err
+	}
+	if _, err := h.Write(content); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// globby expands the given glob patterns, rooted at base, returning absolute
+// paths. Patterns prefixed with "!" are treated as exclusions.
+func globby(base turbopath.AbsoluteSystemPath, patterns []string) ([]string, error) {
+	included := map[string]bool{}
+	excluded := map[string]bool{}
+
+	for _, pattern := range patterns {
+		target := included
+		glob := pattern
+		if strings.HasPrefix(pattern, "!") {
+			target = excluded
+			glob = pattern[1:]
+		}
+
+		matches, err := fs.GlobFiles(base.ToString(), glob)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			target[match] = true
+		}
+	}
+
+	out := make([]string, 0, len(included))
+	for match := range included {
+		if !excluded[match] {
+			out = append(out, match)
+		}
+	}
+	return out, nil
+}
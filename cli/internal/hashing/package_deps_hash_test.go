@@ -209,7 +209,23 @@ func requireGitCmd(t *testing.T, repoRoot turbopath.AbsoluteSystemPath, args ...
 	}
 }
 
+// backendsUnderTest is the set of HashBackend values the shared test tables
+// below run against, so that both backends are held to the same fixtures
+// instead of the GoGit backend getting its own narrower, hand-rolled tests.
+var backendsUnderTest = []HashBackend{Git, GoGit}
+
 func TestGetPackageDeps(t *testing.T) {
+	for _, backend := range backendsUnderTest {
+		backend := backend
+		t.Run(string(backend), func(t *testing.T) {
+			SetHashBackend(backend)
+			defer SetHashBackend(Git)
+			testGetPackageDeps(t)
+		})
+	}
+}
+
+func testGetPackageDeps(t *testing.T) {
 	// Directory structure:
 	// <root>/
 	//   new-root-file <- new file not added to git
@@ -364,6 +380,17 @@ func TestGetPackageDeps(t *testing.T) {
 }
 
 func Test_getPackageFileHashesFromProcessingGitIgnore(t *testing.T) {
+	for _, backend := range backendsUnderTest {
+		backend := backend
+		t.Run(string(backend), func(t *testing.T) {
+			SetHashBackend(backend)
+			defer SetHashBackend(Git)
+			test_getPackageFileHashesFromProcessingGitIgnore(t)
+		})
+	}
+}
+
+func test_getPackageFileHashesFromProcessingGitIgnore(t *testing.T) {
 	rootIgnore := strings.Join([]string{
 		"ignoreme",
 		"ignorethisdir/",
@@ -0,0 +1,153 @@
+package hashing
+
+import (
+	"bufio"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/vercel/turbo/cli/internal/turbopath"
+)
+
+// SubmoduleMode controls how getPackageFileHashesFromGitIndex treats package
+// directories that contain, or live inside, a git submodule.
+type SubmoduleMode string
+
+const (
+	// Ignore is the historical behavior: files inside a submodule are
+	// silently omitted, since the outer repo's `git ls-files` never
+	// descends into one.
+	Ignore SubmoduleMode = "ignore"
+	// CommitSha treats the submodule as a single opaque unit, using the
+	// gitlink's pinned commit SHA as its hash. This is cheap and correct as
+	// long as the submodule's working tree matches what's pinned.
+	CommitSha SubmoduleMode = "commit-sha"
+	// Recurse runs the same hashing pipeline inside the submodule and
+	// merges the results back in under the outer repo's paths, so that
+	// uncommitted changes inside the submodule affect the cache key too.
+	Recurse SubmoduleMode = "recurse"
+)
+
+// submodule describes one entry in .gitmodules, anchored to the repo that
+// contains it.
+type submodule struct {
+	// path is the submodule's location, anchored to the repo that contains
+	// the .gitmodules file declaring it.
+	path turbopath.AnchoredUnixPath
+}
+
+// readGitmodules parses the .gitmodules file at repoRoot, if any, returning
+// the path of each declared submodule. Only `path = ...` is needed here; the
+// gitlink's commit comes from the index, not from .gitmodules.
+func readGitmodules(repoRoot turbopath.AbsoluteSystemPath) ([]submodule, error) {
+	gitmodulesPath := repoRoot.UntypedJoin(".gitmodules")
+	content, err := gitmodulesPath.ReadFile()
+	if err != nil {
+		return nil, nil
+	}
+
+	var submodules []submodule
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "path") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		path := strings.TrimSpace(parts[1])
+		submodules = append(submodules, submodule{path: turbopath.AnchoredUnixPath(path)})
+	}
+	return submodules, nil
+}
+
+// gitlinkSHA returns the commit SHA pinned in the index for the gitlink
+// (mode 160000) at anchoredPath, or an error if it is not a gitlink.
+func gitlinkSHA(repoRoot turbopath.AbsoluteSystemPath, anchoredPath turbopath.AnchoredUnixPath) (string, error) {
+	cmd := exec.Command("git", "ls-files", "-s", "--", anchoredPath.ToString())
+	cmd.Dir = repoRoot.ToString()
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "could not inspect gitlink at %v", anchoredPath)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) < 2 || fields[0] != "160000" {
+		return "", errors.Errorf("%v is not a submodule gitlink", anchoredPath)
+	}
+	return fields[1], nil
+}
+
+// submodulesOverlapping returns every submodule declared in repoRoot's own
+// .gitmodules whose path overlaps packagePath, i.e. the submodule is inside
+// the package, or the package is inside (or equal to) the submodule.
+// Submodules nested inside those are not listed here.
+func submodulesOverlapping(repoRoot turbopath.AbsoluteSystemPath, packagePath turbopath.AnchoredSystemPath) ([]submodule, error) {
+	all, err := readGitmodules(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgUnix := packagePath.ToUnixPath()
+	var overlapping []submodule
+	for _, sub := range all {
+		if sub.path.ToSystemPath().HasPrefix(pkgUnix.ToSystemPath()) || pkgUnix.ToSystemPath().HasPrefix(sub.path.ToSystemPath()) {
+			overlapping = append(overlapping, sub)
+		}
+	}
+	return overlapping, nil
+}
+
+// hashSubmodule computes hashes for a single submodule under opts's
+// SubmoduleMode, returning them anchored to the outer repo's root so they
+// can be merged into the caller's rootPath-anchored hash map before it gets
+// stripped down to packagePath. opts is passed through unchanged to the
+// Recurse case's inner call, so a submodule that itself contains another
+// submodule keeps recursing (and keeps honoring ResolveLFS/HashStrategy)
+// instead of silently falling back to Ignore one level down.
+//
+// packagePath may be nested inside the submodule rather than containing it
+// (submodulesOverlapping matches both directions); when it is, scoped holds
+// packagePath's location relative to the submodule root, and the result is
+// limited (and re-anchored) to just that subtree instead of the whole
+// submodule.
+func hashSubmodule(repoRoot turbopath.AbsoluteSystemPath, sub submodule, packagePath turbopath.AnchoredSystemPath, opts *PackageDepsOptions) (map[turbopath.AnchoredUnixPath]string, error) {
+	pkgUnix := packagePath.ToUnixPath()
+	var scoped turbopath.AnchoredSystemPath
+	if pkgUnix != sub.path && pkgUnix.ToSystemPath().HasPrefix(sub.path.ToSystemPath()) {
+		scoped = turbopath.AnchoredUnixPath(strings.TrimPrefix(pkgUnix.ToString(), sub.path.ToString()+"/")).ToSystemPath()
+	}
+
+	switch opts.defaultedSubmoduleMode() {
+	case CommitSha:
+		if scoped != "" {
+			return nil, errors.Errorf("%v: commit-sha submodule mode cannot scope to %v, a subtree of the submodule", sub.path, packagePath)
+		}
+		sha, err := gitlinkSHA(repoRoot, sub.path)
+		if err != nil {
+			return nil, err
+		}
+		return map[turbopath.AnchoredUnixPath]string{sub.path: sha}, nil
+	case Recurse:
+		subRoot := sub.path.ToSystemPath().RestoreAnchor(repoRoot)
+		hashes, err := getPackageFileHashesFromGitIndex(subRoot, scoped, opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not hash submodule %v", sub.path)
+		}
+		// When scoped, hashes are already relative to packagePath, so
+		// re-anchor under packagePath rather than the whole submodule.
+		anchorPrefix := sub.path
+		if scoped != "" {
+			anchorPrefix = pkgUnix
+		}
+		anchored := make(map[turbopath.AnchoredUnixPath]string, len(hashes))
+		for path, hash := range hashes {
+			anchored[turbopath.AnchoredUnixPath(anchorPrefix.ToString()+"/"+path.ToString())] = hash
+		}
+		return anchored, nil
+	default: // Ignore
+		return nil, nil
+	}
+}
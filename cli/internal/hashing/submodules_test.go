@@ -0,0 +1,194 @@
+package hashing
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vercel/turbo/cli/internal/fs"
+	"github.com/vercel/turbo/cli/internal/turbopath"
+	"gotest.tools/v3/assert"
+)
+
+// setupSubmoduleFixture builds:
+//
+//	<root>/
+//	  outer-file
+//	  my-pkg/
+//	    package.json
+//	    libs/
+//	      vendored/      <- a git submodule
+//	        tracked-file
+//	        untracked-file  <- not added to the submodule's git
+func setupSubmoduleFixture(t *testing.T) (repoRoot turbopath.AbsoluteSystemPath, subPath turbopath.AnchoredSystemPath) {
+	t.Helper()
+
+	repoRoot = fs.AbsoluteSystemPathFromUpstream(t.TempDir())
+	subRoot := repoRoot.UntypedJoin("my-pkg", "libs", "vendored")
+	assert.NilError(t, subRoot.MkdirAll(0775), "CreateDir")
+
+	requireGitCmd(t, subRoot, "init", ".")
+	requireGitCmd(t, subRoot, "config", "--local", "user.name", "test")
+	requireGitCmd(t, subRoot, "config", "--local", "user.email", "test@example.com")
+	assert.NilError(t, subRoot.UntypedJoin("tracked-file").WriteFile([]byte("tracked"), 0644), "WriteFile")
+	requireGitCmd(t, subRoot, "add", ".")
+	requireGitCmd(t, subRoot, "commit", "-m", "submodule commit")
+	assert.NilError(t, subRoot.UntypedJoin("untracked-file").WriteFile([]byte("untracked"), 0644), "WriteFile")
+
+	assert.NilError(t, repoRoot.UntypedJoin("my-pkg", "package.json").WriteFile([]byte("{}"), 0644), "WriteFile")
+
+	requireGitCmd(t, repoRoot, "init", ".")
+	requireGitCmd(t, repoRoot, "config", "--local", "user.name", "test")
+	requireGitCmd(t, repoRoot, "config", "--local", "user.email", "test@example.com")
+	requireGitCmd(t, repoRoot, "-c", "protocol.file.allow=always", "submodule", "add", "./my-pkg/libs/vendored", "my-pkg/libs/vendored")
+	requireGitCmd(t, repoRoot, "add", ".")
+	requireGitCmd(t, repoRoot, "commit", "-m", "outer commit")
+
+	return repoRoot, turbopath.AnchoredSystemPath("my-pkg/libs/vendored")
+}
+
+func TestSubmodulesIgnoredByDefault(t *testing.T) {
+	repoRoot, _ := setupSubmoduleFixture(t)
+	hashes, err := GetPackageFileHashesWithOptions(repoRoot, "my-pkg", nil, nil)
+	assert.NilError(t, err)
+	_, hasTracked := hashes["libs/vendored/tracked-file"]
+	assert.Assert(t, !hasTracked, "expected submodule contents to be ignored by default")
+}
+
+func TestSubmodulesCommitSha(t *testing.T) {
+	repoRoot, subPath := setupSubmoduleFixture(t)
+	hashes, err := GetPackageFileHashesWithOptions(repoRoot, "my-pkg", nil, &PackageDepsOptions{SubmoduleMode: CommitSha})
+	assert.NilError(t, err)
+
+	gitlink, ok := hashes["libs/vendored"]
+	assert.Assert(t, ok, "expected a single opaque hash for the submodule gitlink")
+	assert.Equal(t, len(gitlink), 40, "expected a full git commit sha")
+	_ = subPath
+}
+
+func TestSubmodulesRecurse(t *testing.T) {
+	repoRoot, _ := setupSubmoduleFixture(t)
+	hashes, err := GetPackageFileHashesWithOptions(repoRoot, "my-pkg", nil, &PackageDepsOptions{SubmoduleMode: Recurse})
+	assert.NilError(t, err)
+
+	_, hasTracked := hashes["libs/vendored/tracked-file"]
+	assert.Assert(t, hasTracked, "expected recursed submodule's tracked file to be present")
+	_, hasUntracked := hashes["libs/vendored/untracked-file"]
+	assert.Assert(t, hasUntracked, "expected recursed submodule's untracked file to be present")
+}
+
+// setupNestedSubmoduleFixture builds the same layout as
+// setupSubmoduleFixture, except the "vendored" submodule itself contains
+// another submodule, "nested-lib", so that a Recurse request has to descend
+// more than one level to see everything.
+func setupNestedSubmoduleFixture(t *testing.T) turbopath.AbsoluteSystemPath {
+	t.Helper()
+
+	repoRoot := fs.AbsoluteSystemPathFromUpstream(t.TempDir())
+
+	nestedRoot := repoRoot.UntypedJoin("nested-lib")
+	assert.NilError(t, nestedRoot.MkdirAll(0775), "CreateDir")
+	requireGitCmd(t, nestedRoot, "init", ".")
+	requireGitCmd(t, nestedRoot, "config", "--local", "user.name", "test")
+	requireGitCmd(t, nestedRoot, "config", "--local", "user.email", "test@example.com")
+	assert.NilError(t, nestedRoot.UntypedJoin("nested-tracked-file").WriteFile([]byte("nested"), 0644), "WriteFile")
+	requireGitCmd(t, nestedRoot, "add", ".")
+	requireGitCmd(t, nestedRoot, "commit", "-m", "nested commit")
+
+	subRoot := repoRoot.UntypedJoin("my-pkg", "libs", "vendored")
+	assert.NilError(t, subRoot.MkdirAll(0775), "CreateDir")
+	requireGitCmd(t, subRoot, "init", ".")
+	requireGitCmd(t, subRoot, "config", "--local", "user.name", "test")
+	requireGitCmd(t, subRoot, "config", "--local", "user.email", "test@example.com")
+	assert.NilError(t, subRoot.UntypedJoin("tracked-file").WriteFile([]byte("tracked"), 0644), "WriteFile")
+	requireGitCmd(t, subRoot, "-c", "protocol.file.allow=always", "submodule", "add", "../../../nested-lib", "nested-lib")
+	requireGitCmd(t, subRoot, "add", ".")
+	requireGitCmd(t, subRoot, "commit", "-m", "submodule commit")
+
+	assert.NilError(t, repoRoot.UntypedJoin("my-pkg", "package.json").WriteFile([]byte("{}"), 0644), "WriteFile")
+
+	requireGitCmd(t, repoRoot, "init", ".")
+	requireGitCmd(t, repoRoot, "config", "--local", "user.name", "test")
+	requireGitCmd(t, repoRoot, "config", "--local", "user.email", "test@example.com")
+	requireGitCmd(t, repoRoot, "-c", "protocol.file.allow=always", "submodule", "add", "./my-pkg/libs/vendored", "my-pkg/libs/vendored")
+	requireGitCmd(t, repoRoot, "add", ".")
+	requireGitCmd(t, repoRoot, "commit", "-m", "outer commit")
+
+	return repoRoot
+}
+
+func TestSubmodulesRecurseNested(t *testing.T) {
+	repoRoot := setupNestedSubmoduleFixture(t)
+	hashes, err := GetPackageFileHashesWithOptions(repoRoot, "my-pkg", nil, &PackageDepsOptions{SubmoduleMode: Recurse})
+	assert.NilError(t, err)
+
+	_, hasTracked := hashes["libs/vendored/tracked-file"]
+	assert.Assert(t, hasTracked, "expected the outer submodule's tracked file to be present")
+	_, hasNested := hashes["libs/vendored/nested-lib/nested-tracked-file"]
+	assert.Assert(t, hasNested, "expected a submodule nested inside another submodule to be recursed into too")
+}
+
+// setupSubmoduleFixtureWithSubPackage builds:
+//
+//	<root>/
+//	  my-pkg/
+//	    package.json
+//	    libs/
+//	      vendored/        <- a git submodule
+//	        subdir-a/      <- the package actually being hashed
+//	          package.json
+//	          file-a
+//	        subdir-b/      <- a sibling inside the submodule, outside the package
+//	          file-b
+//
+// i.e. the requested package lives INSIDE the submodule, rather than
+// containing it.
+func setupSubmoduleFixtureWithSubPackage(t *testing.T) (repoRoot turbopath.AbsoluteSystemPath, packagePath turbopath.AnchoredSystemPath) {
+	t.Helper()
+
+	repoRoot = fs.AbsoluteSystemPathFromUpstream(t.TempDir())
+	subRoot := repoRoot.UntypedJoin("my-pkg", "libs", "vendored")
+	assert.NilError(t, subRoot.UntypedJoin("subdir-a").MkdirAll(0775), "CreateDir")
+	assert.NilError(t, subRoot.UntypedJoin("subdir-b").MkdirAll(0775), "CreateDir")
+
+	requireGitCmd(t, subRoot, "init", ".")
+	requireGitCmd(t, subRoot, "config", "--local", "user.name", "test")
+	requireGitCmd(t, subRoot, "config", "--local", "user.email", "test@example.com")
+	assert.NilError(t, subRoot.UntypedJoin("subdir-a", "package.json").WriteFile([]byte("{}"), 0644), "WriteFile")
+	assert.NilError(t, subRoot.UntypedJoin("subdir-a", "file-a").WriteFile([]byte("file-a contents"), 0644), "WriteFile")
+	assert.NilError(t, subRoot.UntypedJoin("subdir-b", "file-b").WriteFile([]byte("file-b contents"), 0644), "WriteFile")
+	requireGitCmd(t, subRoot, "add", ".")
+	requireGitCmd(t, subRoot, "commit", "-m", "submodule commit")
+
+	assert.NilError(t, repoRoot.UntypedJoin("my-pkg", "package.json").WriteFile([]byte("{}"), 0644), "WriteFile")
+
+	requireGitCmd(t, repoRoot, "init", ".")
+	requireGitCmd(t, repoRoot, "config", "--local", "user.name", "test")
+	requireGitCmd(t, repoRoot, "config", "--local", "user.email", "test@example.com")
+	requireGitCmd(t, repoRoot, "-c", "protocol.file.allow=always", "submodule", "add", "./my-pkg/libs/vendored", "my-pkg/libs/vendored")
+	requireGitCmd(t, repoRoot, "add", ".")
+	requireGitCmd(t, repoRoot, "commit", "-m", "outer commit")
+
+	return repoRoot, turbopath.AnchoredSystemPath("my-pkg/libs/vendored/subdir-a")
+}
+
+func TestSubmodulesRecurseScopedToSubPackage(t *testing.T) {
+	repoRoot, packagePath := setupSubmoduleFixtureWithSubPackage(t)
+	hashes, err := GetPackageFileHashesWithOptions(repoRoot, packagePath, nil, &PackageDepsOptions{SubmoduleMode: Recurse})
+	assert.NilError(t, err)
+
+	_, hasFileA := hashes["file-a"]
+	assert.Assert(t, hasFileA, "expected the requested subdirectory's own file to be present")
+
+	for path := range hashes {
+		assert.Assert(t, !strings.Contains(path.ToString(), "subdir-b"),
+			"expected a sibling directory outside the requested package to be excluded, got %v", path)
+		assert.Assert(t, !strings.HasPrefix(path.ToString(), "my-pkg/"),
+			"expected hashes to be relative to the requested package, got repo-root-anchored key %v", path)
+	}
+}
+
+func TestSubmodulesCommitShaRejectsSubPackage(t *testing.T) {
+	repoRoot, packagePath := setupSubmoduleFixtureWithSubPackage(t)
+	_, err := GetPackageFileHashesWithOptions(repoRoot, packagePath, nil, &PackageDepsOptions{SubmoduleMode: CommitSha})
+	assert.Assert(t, err != nil, "expected commit-sha mode to reject a package path nested inside a submodule")
+}